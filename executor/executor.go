@@ -3,10 +3,20 @@
 package executor
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"errors"
+	"fmt"
 	"io"
+	"math/rand"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
 	"time"
 )
 
@@ -16,10 +26,26 @@ type Config struct {
 	args       []string
 	env        []string
 	dir        string
+	stdin      io.Reader
 	stdout     io.Writer
 	stderr     io.Writer
 	credential *Credential // platform-specific
 	maxOutput  int64
+	pty        bool
+	ptyRows    uint16
+	ptyCols    uint16
+
+	gracefulSignal os.Signal
+	gracefulGrace  time.Duration
+
+	stdoutLineFn func(line string)
+	stderrLineFn func(line string)
+	outputChan   chan<- OutputEvent
+	slowConsumer SlowConsumerPolicy
+
+	rlimits      map[int]Rlimit // Linux only; ignored on other platforms
+	cgroup       *CgroupSpec    // Linux only
+	killStrategy KillStrategy
 }
 
 // Option configures a command execution.
@@ -39,6 +65,10 @@ func WithDir(dir string) Option {
 	return func(c *Config) { c.dir = dir }
 }
 
+func WithStdin(r io.Reader) Option {
+	return func(c *Config) { c.stdin = r }
+}
+
 func WithStdout(w io.Writer) Option {
 	return func(c *Config) { c.stdout = w }
 }
@@ -55,11 +85,182 @@ func WithMaxOutput(n int64) Option {
 	return func(c *Config) { c.maxOutput = n }
 }
 
+// WithPTY allocates a pseudo-terminal for the child and attaches its
+// stdin/stdout/stderr to the PTY master. This is required for commands
+// that branch on isatty (interactive shells, sudo prompts, colorized
+// output). It is only supported on Unix; Run returns ErrPTYUnsupported
+// on Windows. Because a PTY merges stdout and stderr onto a single
+// stream, it cannot be combined with WithStderrLine or
+// WithOutputChannel; Start returns ErrPTYStreamSplit if either is set.
+func WithPTY() Option {
+	return func(c *Config) { c.pty = true }
+}
+
+// WithPTYSize sets the initial PTY window size. It implies WithPTY.
+func WithPTYSize(rows, cols uint16) Option {
+	return func(c *Config) {
+		c.pty = true
+		c.ptyRows = rows
+		c.ptyCols = cols
+	}
+}
+
+// WithGracefulShutdown changes how context cancellation terminates the
+// child: instead of killing it immediately, sig is sent first (e.g.
+// os.Interrupt or syscall.SIGTERM; on Unix, to the child's process
+// group), and only after grace elapses without
+// the process exiting does the runner escalate to its normal kill
+// behavior. This mirrors how Docker/containerd stop containers.
+func WithGracefulShutdown(sig os.Signal, grace time.Duration) Option {
+	return func(c *Config) {
+		c.gracefulSignal = sig
+		c.gracefulGrace = grace
+	}
+}
+
+// WithStdoutLine registers fn to be called with each line of stdout as it
+// arrives, in order. See WithSlowConsumerPolicy for what happens if fn
+// can't keep up.
+func WithStdoutLine(fn func(line string)) Option {
+	return func(c *Config) { c.stdoutLineFn = fn }
+}
+
+// WithStderrLine registers fn to be called with each line of stderr as it
+// arrives, in order. See WithSlowConsumerPolicy for what happens if fn
+// can't keep up. Incompatible with WithPTY: see its doc comment.
+func WithStderrLine(fn func(line string)) Option {
+	return func(c *Config) { c.stderrLineFn = fn }
+}
+
+// WithOutputChannel delivers every line of stdout and stderr to ch, in
+// order, as it arrives. See WithSlowConsumerPolicy for what happens if
+// the receiver can't keep up. Incompatible with WithPTY: see its doc
+// comment.
+func WithOutputChannel(ch chan<- OutputEvent) Option {
+	return func(c *Config) { c.outputChan = ch }
+}
+
+// WithSlowConsumerPolicy controls what happens when a WithStdoutLine /
+// WithStderrLine callback or a WithOutputChannel receiver is slower than
+// the child's output. Defaults to SlowConsumerBlock.
+func WithSlowConsumerPolicy(policy SlowConsumerPolicy) Option {
+	return func(c *Config) { c.slowConsumer = policy }
+}
+
+// Rlimit is a single resource limit's soft (Cur) and hard (Max) values,
+// as understood by setrlimit/prlimit. Linux only; has no effect on
+// other platforms, including other Unix systems.
+type Rlimit struct {
+	Cur uint64
+	Max uint64
+}
+
+// WithRLimits applies the given resource limits (keyed by RLIMIT_* from
+// the syscall package, e.g. syscall.RLIMIT_NOFILE) to the child
+// immediately after it starts, before it does meaningful work.
+func WithRLimits(limits map[int]Rlimit) Option {
+	return func(c *Config) { c.rlimits = limits }
+}
+
+// CgroupSpec configures a cgroup v2 scope the child is placed into.
+// Linux only; WithCgroup is a no-op elsewhere.
+type CgroupSpec struct {
+	// Parent is the slice the scope is created under, relative to
+	// /sys/fs/cgroup. Defaults to "executor.slice".
+	Parent string
+	// CPUQuota is written verbatim to the scope's cpu.max (e.g.
+	// "50000 100000" for 50% of one CPU).
+	CPUQuota string
+	// MemoryMax is written to memory.max, in bytes. Zero leaves the
+	// parent's limit in place.
+	MemoryMax int64
+	// PIDsMax is written to pids.max. Zero leaves the parent's limit in
+	// place.
+	PIDsMax int64
+	// IOWeight is written to io.weight. Zero leaves the parent's weight
+	// in place.
+	IOWeight int
+}
+
+// WithCgroup runs the child inside a freshly created cgroup v2 scope
+// configured per spec, removing the scope once the command completes.
+// Only supported on Linux.
+func WithCgroup(spec CgroupSpec) Option {
+	return func(c *Config) { c.cgroup = &spec }
+}
+
+// KillStrategy selects how a command's process tree is torn down on
+// context cancellation (after any WithGracefulShutdown grace period
+// elapses, or immediately if none was configured).
+type KillStrategy int
+
+const (
+	// KillPGID sends SIGKILL to the child's process group. This is the
+	// default; it misses grandchildren that called setsid to escape the
+	// group.
+	KillPGID KillStrategy = iota
+	// KillProcessTree walks /proc to find every descendant of the child
+	// transitively (via PPid) and SIGKILLs each, repeating until no new
+	// descendants appear. Linux only; falls back to KillPGID elsewhere.
+	KillProcessTree
+	// KillCgroup writes to the cgroup.kill of the scope created by
+	// WithCgroup, which the kernel guarantees kills every process inside
+	// it regardless of setsid/PID-reuse races. Requires WithCgroup;
+	// falls back to KillPGID otherwise.
+	KillCgroup
+	// KillPidfd uses pidfd_open/pidfd_send_signal to target the exact
+	// process, avoiding PID-reuse races. Linux only; falls back to
+	// KillPGID elsewhere.
+	KillPidfd
+)
+
+// WithKillStrategy selects how the process tree is torn down on context
+// cancellation and as the final escalation after WithGracefulShutdown.
+// Whatever strategy is chosen, if the process is still alive shortly
+// after and a cgroup was created via WithCgroup, KillCgroup is used as a
+// last resort. Defaults to KillPGID.
+func WithKillStrategy(strategy KillStrategy) Option {
+	return func(c *Config) { c.killStrategy = strategy }
+}
+
+// Stream identifies which of the child's output streams a line came from.
+type Stream int
+
+const (
+	Stdout Stream = iota
+	Stderr
+)
+
+// OutputEvent is one line of output delivered via WithOutputChannel.
+type OutputEvent struct {
+	Stream Stream
+	Line   string
+	Time   time.Time
+}
+
+// SlowConsumerPolicy controls the backpressure semantics of
+// WithStdoutLine, WithStderrLine, and WithOutputChannel.
+type SlowConsumerPolicy int
+
+const (
+	// SlowConsumerBlock backpressures all the way to the child: once the
+	// internal relay catches up to a slow callback/channel, the child's
+	// writes to stdout/stderr block until it drains. This is the default.
+	SlowConsumerBlock SlowConsumerPolicy = iota
+	// SlowConsumerDrop never blocks the child. Lines are buffered
+	// internally; once that buffer is full, further lines are dropped
+	// and counted in Result.DroppedLines instead of waiting.
+	SlowConsumerDrop
+)
+
 // --- Errors ---
 
 var (
-	ErrContextDone = errors.New("executor: context timeout or cancelled")
-	ErrStart       = errors.New("executor: failed to start")
+	ErrContextDone       = errors.New("executor: context timeout or cancelled")
+	ErrStart             = errors.New("executor: failed to start")
+	ErrPTYUnsupported    = errors.New("executor: pty allocation is not supported on this platform")
+	ErrPTYStreamSplit    = errors.New("executor: pty merges stdout and stderr; WithStderrLine and WithOutputChannel require separate streams")
+	ErrCgroupUnsupported = errors.New("executor: cgroups are only supported on Linux")
 )
 
 // Result represents command execution result.
@@ -71,11 +272,99 @@ type Result struct {
 	Duration        time.Duration
 	StdoutTruncated bool
 	StderrTruncated bool
+	DroppedLines    int64
+	ResourceUsage   ResourceUsage
+
+	combined string
+}
+
+// ResourceUsage reports resource consumption gathered from the OS via
+// getrusage/wait4, plus (on Linux, with WithCgroup) the cgroup's peak
+// memory. It is zero-valued on platforms/paths that don't populate it.
+type ResourceUsage struct {
+	MaxRSS       int64 // peak resident set size, in bytes
+	UserCPU      time.Duration
+	SysCPU       time.Duration
+	PeakMemBytes int64 // cgroup memory.peak; Linux + WithCgroup only
+}
+
+// Success reports whether the command started, ran to completion, and
+// exited with code 0.
+func (r Result) Success() bool {
+	return r.Err == nil && r.ExitCode == 0
+}
+
+// TimedOut reports whether the command was terminated because its
+// context was cancelled or timed out.
+func (r Result) TimedOut() bool {
+	return errors.Is(r.Err, ErrContextDone)
+}
+
+// Combined returns stdout and stderr interleaved in the order the runner
+// read them from the child. Each stream keeps its own order; between
+// the two streams, output written close together may appear in a
+// different order than the child wrote it.
+func (r Result) Combined() string {
+	return r.combined
+}
+
+// --- assertion helpers ---
+
+// Expected describes the outcome a Result is expected to have, for use
+// with Result.Assert in tests. Borrowed from the pattern in Docker's
+// icmd.Expected: fill in only the fields relevant to the assertion.
+// ExitCode is always compared, so its zero value expects exit code 0;
+// a timeout or a start failure needs ExitCode: -1 as well.
+type Expected struct {
+	ExitCode    int
+	Err         error
+	Out         string
+	OutContains string
+	OutMatches  *regexp.Regexp
+	Stderr      string
+	Timeout     bool
+}
+
+// Assert fails t if r does not match exp.
+func (r Result) Assert(t testing.TB, exp Expected) {
+	t.Helper()
+
+	if exp.Timeout {
+		if !r.TimedOut() {
+			t.Fatalf("executor: expected a timeout, got err=%v exitCode=%d", r.Err, r.ExitCode)
+		}
+	} else if exp.Err != nil {
+		if !errors.Is(r.Err, exp.Err) {
+			t.Fatalf("executor: expected error %v, got %v", exp.Err, r.Err)
+		}
+	} else if r.Err != nil {
+		t.Fatalf("executor: unexpected error: %v", r.Err)
+	}
+
+	if r.ExitCode != exp.ExitCode {
+		t.Fatalf("executor: expected exit code %d, got %d (stdout=%q stderr=%q)", exp.ExitCode, r.ExitCode, r.Stdout, r.Stderr)
+	}
+	if exp.Out != "" && r.Stdout != exp.Out {
+		t.Fatalf("executor: expected stdout %q, got %q", exp.Out, r.Stdout)
+	}
+	if exp.OutContains != "" && !strings.Contains(r.Stdout, exp.OutContains) {
+		t.Fatalf("executor: expected stdout to contain %q, got %q", exp.OutContains, r.Stdout)
+	}
+	if exp.OutMatches != nil && !exp.OutMatches.MatchString(r.Stdout) {
+		t.Fatalf("executor: expected stdout to match %q, got %q", exp.OutMatches.String(), r.Stdout)
+	}
+	if exp.Stderr != "" && !strings.Contains(r.Stderr, exp.Stderr) {
+		t.Fatalf("executor: expected stderr to contain %q, got %q", exp.Stderr, r.Stderr)
+	}
 }
 
 // --- limited buffer helper ---
 
+// limitedBuffer is safe for concurrent writes: the combined buffer (see
+// Result.Combined) is written from the stdout and stderr copy goroutines
+// at the same time, and needs the interleaving to stay intact.
 type limitedBuffer struct {
+	mu        sync.Mutex
 	buf       bytes.Buffer
 	limit     int64
 	written   int64
@@ -87,6 +376,9 @@ func newLimitedBuffer(limit int64) *limitedBuffer {
 }
 
 func (b *limitedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
 	if b.limit <= 0 {
 		n, _ := b.buf.Write(p)
 		b.written += int64(n)
@@ -108,15 +400,43 @@ func (b *limitedBuffer) Write(p []byte) (int, error) {
 	return len(p), nil
 }
 
-func (b *limitedBuffer) String() string { return b.buf.String() }
+func (b *limitedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
 
 // --- platform-agnostic runner ---
 
-func Run(ctx context.Context, cmd string, opts ...Option) Result {
-	if cmd == "" {
-		return Result{Err: errors.New("executor: command cannot be empty"), ExitCode: -1}
-	}
+// Cmd is a prepared, not-yet-started command. It mirrors the os/exec.Cmd
+// model: Start and Wait are separate so callers can obtain the PID, send
+// signals mid-run, or stream output via the *Pipe methods while doing
+// other work. Use Prepare to create one.
+type Cmd struct {
+	ctx context.Context
+	cfg *Config
 
+	execCmd *exec.Cmd
+	ptmx    *os.File // non-nil only when cfg.pty is set and Start succeeded (Unix)
+
+	stdoutBuf   *limitedBuffer
+	stderrBuf   *limitedBuffer
+	combinedBuf *limitedBuffer
+	closers     []io.Closer
+	lineStreams []*lineStream
+	cgroupState *cgroupState
+
+	copyDone  chan struct{}
+	winchStop func()
+
+	start    time.Time
+	started  bool
+	finished chan struct{}
+	result   Result
+}
+
+// Prepare builds a Cmd without starting it.
+func Prepare(ctx context.Context, cmd string, opts ...Option) *Cmd {
 	cfg := &Config{
 		cmd:       cmd,
 		stdout:    io.Discard,
@@ -126,7 +446,367 @@ func Run(ctx context.Context, cmd string, opts ...Option) Result {
 	for _, opt := range opts {
 		opt(cfg)
 	}
+	return &Cmd{ctx: ctx, cfg: cfg}
+}
+
+// Start begins execution and returns once the child has been launched
+// (or failed to launch); it does not wait for completion.
+func (c *Cmd) Start() error {
+	if c.cfg.cmd == "" {
+		return errors.New("executor: command cannot be empty")
+	}
+	if c.started {
+		return errors.New("executor: command already started")
+	}
+	if c.cfg.pty && (c.cfg.stderrLineFn != nil || c.cfg.outputChan != nil) {
+		return ErrPTYStreamSplit
+	}
+	c.started = true
+
+	// finished is created (and closed on any error return below) before
+	// anything else can fail, so Wait called after a failed Start returns
+	// immediately instead of blocking on a nil/never-closed channel.
+	c.finished = make(chan struct{})
+
+	c.stdoutBuf = newLimitedBuffer(c.cfg.maxOutput)
+	c.stderrBuf = newLimitedBuffer(c.cfg.maxOutput)
+	c.combinedBuf = newLimitedBuffer(c.cfg.maxOutput)
+
+	// platform-specific exec setup handled in executor_unix.go / executor_windows.go
+	if err := startPlatform(c); err != nil {
+		c.result = Result{Err: err, ExitCode: -1}
+		close(c.finished)
+		return err
+	}
+
+	if c.cfg.cgroup != nil {
+		state, err := applyCgroup(c)
+		if err != nil {
+			killPlatform(c)
+			_ = c.execCmd.Wait()
+			err = fmt.Errorf("%w: %v", ErrStart, err)
+			c.result = Result{Err: err, ExitCode: -1}
+			close(c.finished)
+			return err
+		}
+		c.cgroupState = state
+	}
+
+	c.start = time.Now()
+
+	procDone := make(chan error, 1)
+	go func() { procDone <- c.execCmd.Wait() }()
+	go c.run(procDone)
+
+	return nil
+}
+
+// run owns the child's lifetime: it waits for either process exit or
+// context cancellation (escalating through WithGracefulShutdown, then the
+// platform's normal kill behavior), then assembles the final Result.
+func (c *Cmd) run(procDone chan error) {
+	var waitErr error
+	select {
+	case <-c.ctx.Done():
+		if c.cfg.gracefulSignal != nil && c.cfg.gracefulGrace > 0 {
+			_ = sendSignalPlatform(c, c.cfg.gracefulSignal)
+			select {
+			case waitErr = <-procDone:
+				c.finish(fmt.Errorf("%w: %v", ErrContextDone, waitErr))
+				return
+			case <-time.After(c.cfg.gracefulGrace):
+			}
+		}
+		killPlatform(c)
+		select {
+		case waitErr = <-procDone:
+		case <-time.After(killEscalationTimeout):
+			// The chosen strategy didn't bring the process down in time;
+			// fall back to cgroup.kill, which the kernel guarantees works.
+			if c.cgroupState != nil {
+				c.cgroupState.kill()
+			}
+			select {
+			case waitErr = <-procDone:
+			case <-time.After(killEscalationTimeout):
+				// Still alive: the strategy and (if any) the cgroup
+				// fallback both failed. Don't wedge Wait forever -- kill
+				// the process group directly as a last resort.
+				killLastResort(c)
+				waitErr = <-procDone
+			}
+		}
+		waitErr = fmt.Errorf("%w: %v", ErrContextDone, waitErr)
+	case err := <-procDone:
+		waitErr = err
+	}
+	c.finish(waitErr)
+}
+
+// killEscalationTimeout bounds how long we wait after killPlatform before
+// falling back to KillCgroup as a last resort (see WithKillStrategy).
+const killEscalationTimeout = 2 * time.Second
+
+// finish assembles the Result and signals any Wait callers.
+func (c *Cmd) finish(waitErr error) {
+	finishPlatform(c)
+
+	duration := time.Since(c.start)
+	exitCode := -1
+	if c.execCmd.ProcessState != nil {
+		exitCode = c.execCmd.ProcessState.ExitCode()
+	} else if waitErr != nil {
+		exitCode = exitCodeFromError(waitErr)
+	}
+
+	usage := resourceUsageFromProcessState(c.execCmd.ProcessState)
+	if c.cgroupState != nil {
+		usage.PeakMemBytes = c.cgroupState.peakMemBytes()
+		c.cgroupState.cleanup()
+	}
+
+	for _, cl := range c.closers {
+		_ = cl.Close()
+	}
+
+	// Closing the pipes above unblocks each line scanner's final Scan, so
+	// waiting on done here guarantees every callback/channel send has
+	// happened before Wait returns.
+	var dropped int64
+	for _, ls := range c.lineStreams {
+		<-ls.done
+		dropped += atomic.LoadInt64(&ls.dropped)
+	}
+
+	c.result = Result{
+		Stdout:          c.stdoutBuf.String(),
+		Stderr:          c.stderrBuf.String(),
+		ExitCode:        exitCode,
+		Err:             waitErr,
+		Duration:        duration,
+		StdoutTruncated: c.stdoutBuf.truncated,
+		StderrTruncated: c.stderrBuf.truncated,
+		DroppedLines:    dropped,
+		ResourceUsage:   usage,
+		combined:        c.combinedBuf.String(),
+	}
+	close(c.finished)
+}
+
+// Wait blocks until the command completes and returns its Result. It is
+// safe to call more than once; later calls return the same Result.
+func (c *Cmd) Wait() Result {
+	if !c.started {
+		return Result{Err: errors.New("executor: Wait called before Start"), ExitCode: -1}
+	}
+	<-c.finished
+	return c.result
+}
+
+// Pid returns the child's process ID, or -1 if it hasn't started.
+func (c *Cmd) Pid() int {
+	if c.execCmd == nil || c.execCmd.Process == nil {
+		return -1
+	}
+	return c.execCmd.Process.Pid
+}
+
+// Signal sends sig to the child process.
+func (c *Cmd) Signal(sig os.Signal) error {
+	if c.execCmd == nil || c.execCmd.Process == nil {
+		return errors.New("executor: command not started")
+	}
+	return c.execCmd.Process.Signal(sig)
+}
+
+// StdinPipe returns a pipe connected to the child's standard input. It
+// must be called before Start.
+func (c *Cmd) StdinPipe() (io.WriteCloser, error) {
+	if c.started {
+		return nil, errors.New("executor: StdinPipe called after Start")
+	}
+	pr, pw := io.Pipe()
+	c.cfg.stdin = pr
+	return pw, nil
+}
 
-	// platform-specific exec and kill logic handled in executor_unix.go / executor_windows.go
-	return runCommand(ctx, cfg)
+// StdoutPipe returns a pipe delivering the child's standard output, in
+// addition to whatever WithStdout already captures. It must be called
+// before Start.
+func (c *Cmd) StdoutPipe() (io.ReadCloser, error) {
+	if c.started {
+		return nil, errors.New("executor: StdoutPipe called after Start")
+	}
+	pr, pw := io.Pipe()
+	c.cfg.stdout = io.MultiWriter(c.cfg.stdout, pw)
+	c.closers = append(c.closers, pw)
+	return pr, nil
+}
+
+// StderrPipe returns a pipe delivering the child's standard error, in
+// addition to whatever WithStderr already captures. It must be called
+// before Start.
+func (c *Cmd) StderrPipe() (io.ReadCloser, error) {
+	if c.started {
+		return nil, errors.New("executor: StderrPipe called after Start")
+	}
+	pr, pw := io.Pipe()
+	c.cfg.stderr = io.MultiWriter(c.cfg.stderr, pw)
+	c.closers = append(c.closers, pw)
+	return pr, nil
+}
+
+// stdoutWriter returns the io.Writer the child's stdout should be
+// connected to: the limited buffer, the combined buffer, the caller's
+// WithStdout writer, and (if configured) a line-oriented relay feeding
+// WithStdoutLine / WithOutputChannel.
+func (c *Cmd) stdoutWriter() io.Writer {
+	writers := []io.Writer{c.stdoutBuf, c.combinedBuf, c.cfg.stdout}
+	if c.cfg.stdoutLineFn != nil || c.cfg.outputChan != nil {
+		writers = append(writers, c.newLineStream(Stdout, c.cfg.stdoutLineFn))
+	}
+	return io.MultiWriter(writers...)
+}
+
+// stderrWriter is stdoutWriter's stderr counterpart.
+func (c *Cmd) stderrWriter() io.Writer {
+	writers := []io.Writer{c.stderrBuf, c.combinedBuf, c.cfg.stderr}
+	if c.cfg.stderrLineFn != nil || c.cfg.outputChan != nil {
+		writers = append(writers, c.newLineStream(Stderr, c.cfg.stderrLineFn))
+	}
+	return io.MultiWriter(writers...)
+}
+
+// lineStream scans a pipe for newline-delimited output and relays each
+// line to a callback and/or an OutputEvent channel, decoupled from the
+// scan loop so a slow consumer never stalls reading from the pipe itself
+// under SlowConsumerDrop.
+type lineStream struct {
+	pw      *io.PipeWriter
+	done    chan struct{}
+	dropped int64
+}
+
+// newLineStream starts the scan and delivery goroutines and returns the
+// pipe writer to plug into the child's stdout/stderr MultiWriter.
+func (c *Cmd) newLineStream(stream Stream, cb func(string)) io.Writer {
+	pr, pw := io.Pipe()
+	ls := &lineStream{pw: pw, done: make(chan struct{})}
+
+	events := make(chan OutputEvent)
+	if c.cfg.slowConsumer == SlowConsumerDrop {
+		events = make(chan OutputEvent, 256)
+	}
+
+	go func() {
+		defer close(events)
+		scanner := bufio.NewScanner(pr)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			ev := OutputEvent{Stream: stream, Line: scanner.Text(), Time: time.Now()}
+			if c.cfg.slowConsumer == SlowConsumerDrop {
+				select {
+				case events <- ev:
+				default:
+					atomic.AddInt64(&ls.dropped, 1)
+				}
+			} else {
+				events <- ev
+			}
+		}
+	}()
+
+	go func() {
+		defer close(ls.done)
+		for ev := range events {
+			if cb != nil {
+				cb(ev.Line)
+			}
+			if c.cfg.outputChan != nil {
+				c.cfg.outputChan <- ev
+			}
+		}
+	}()
+
+	c.lineStreams = append(c.lineStreams, ls)
+	c.closers = append(c.closers, pw)
+	return pw
+}
+
+// Run executes cmd to completion; it is equivalent to calling Start and
+// then Wait on Prepare's result.
+func Run(ctx context.Context, cmd string, opts ...Option) Result {
+	c := Prepare(ctx, cmd, opts...)
+	if err := c.Start(); err != nil {
+		return Result{Err: err, ExitCode: -1}
+	}
+	return c.Wait()
+}
+
+// RetryPolicy controls RunWithRetry's exponential backoff between
+// attempts.
+type RetryPolicy struct {
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Multiplier     float64
+	Jitter         bool
+
+	// RetryOn decides whether a given attempt's Result should be retried.
+	// Defaults to defaultRetryOn if nil.
+	RetryOn func(Result) bool
+}
+
+// defaultRetryOn retries attempts that failed to start or exited
+// non-zero, but not ones cut short by context cancellation: there's no
+// point retrying a command against a deadline that has already passed.
+func defaultRetryOn(res Result) bool {
+	if errors.Is(res.Err, ErrContextDone) {
+		return false
+	}
+	return errors.Is(res.Err, ErrStart) || res.ExitCode != 0
+}
+
+// RunWithRetry runs cmd with Run, retrying with exponential backoff
+// according to policy until an attempt succeeds, RetryOn returns false,
+// MaxAttempts is reached, or ctx is done. It returns every attempt's
+// Result, in order, for callers that want to log or inspect the retry
+// history.
+func RunWithRetry(ctx context.Context, cmd string, policy RetryPolicy, opts ...Option) []Result {
+	retryOn := policy.RetryOn
+	if retryOn == nil {
+		retryOn = defaultRetryOn
+	}
+
+	var results []Result
+	backoff := policy.InitialBackoff
+
+	for attempt := 1; ; attempt++ {
+		res := Run(ctx, cmd, opts...)
+		results = append(results, res)
+
+		if !retryOn(res) {
+			return results
+		}
+		if policy.MaxAttempts > 0 && attempt >= policy.MaxAttempts {
+			return results
+		}
+
+		wait := backoff
+		if policy.Jitter {
+			wait = time.Duration(float64(wait) * (0.5 + rand.Float64()))
+		}
+		select {
+		case <-ctx.Done():
+			return results
+		case <-time.After(wait):
+		}
+
+		if policy.Multiplier > 0 {
+			backoff = time.Duration(float64(backoff) * policy.Multiplier)
+		}
+		if policy.MaxBackoff > 0 && backoff > policy.MaxBackoff {
+			backoff = policy.MaxBackoff
+		}
+	}
 }
@@ -0,0 +1,97 @@
+//go:build linux
+// +build linux
+
+package executor
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// cgroupState tracks the cgroup v2 scope created for a single command so
+// it can be queried for peak memory and removed once the command exits.
+type cgroupState struct {
+	path string
+}
+
+// applyCgroup creates a scope under c.cfg.cgroup.Parent (default
+// "executor.slice"), moves the already-started child into it via
+// cgroup.procs, and applies the requested controllers.
+func applyCgroup(c *Cmd) (*cgroupState, error) {
+	spec := c.cfg.cgroup
+	parent := spec.Parent
+	if parent == "" {
+		parent = "executor.slice"
+	}
+
+	scope := filepath.Join("/sys/fs/cgroup", parent, strconv.Itoa(c.execCmd.Process.Pid)+".scope")
+	if err := os.MkdirAll(scope, 0o755); err != nil {
+		return nil, fmt.Errorf("create cgroup scope: %w", err)
+	}
+	state := &cgroupState{path: scope}
+
+	pid := strconv.Itoa(c.execCmd.Process.Pid)
+	if err := os.WriteFile(filepath.Join(scope, "cgroup.procs"), []byte(pid), 0o644); err != nil {
+		state.cleanup()
+		return nil, fmt.Errorf("move pid %s into cgroup: %w", pid, err)
+	}
+
+	if spec.CPUQuota != "" {
+		if err := os.WriteFile(filepath.Join(scope, "cpu.max"), []byte(spec.CPUQuota), 0o644); err != nil {
+			state.cleanup()
+			return nil, fmt.Errorf("set cpu.max: %w", err)
+		}
+	}
+	if spec.MemoryMax > 0 {
+		v := strconv.FormatInt(spec.MemoryMax, 10)
+		if err := os.WriteFile(filepath.Join(scope, "memory.max"), []byte(v), 0o644); err != nil {
+			state.cleanup()
+			return nil, fmt.Errorf("set memory.max: %w", err)
+		}
+	}
+	if spec.PIDsMax > 0 {
+		v := strconv.FormatInt(spec.PIDsMax, 10)
+		if err := os.WriteFile(filepath.Join(scope, "pids.max"), []byte(v), 0o644); err != nil {
+			state.cleanup()
+			return nil, fmt.Errorf("set pids.max: %w", err)
+		}
+	}
+	if spec.IOWeight > 0 {
+		v := strconv.Itoa(spec.IOWeight)
+		if err := os.WriteFile(filepath.Join(scope, "io.weight"), []byte(v), 0o644); err != nil {
+			state.cleanup()
+			return nil, fmt.Errorf("set io.weight: %w", err)
+		}
+	}
+
+	return state, nil
+}
+
+// peakMemBytes reads the scope's memory.peak, or 0 if it can't be read
+// (e.g. the scope was already removed).
+func (s *cgroupState) peakMemBytes() int64 {
+	data, err := os.ReadFile(filepath.Join(s.path, "memory.peak"))
+	if err != nil {
+		return 0
+	}
+	v, _ := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	return v
+}
+
+// kill writes to cgroup.kill, which the kernel guarantees SIGKILLs every
+// process in the scope regardless of setsid/PID-reuse races.
+func (s *cgroupState) kill() bool {
+	return os.WriteFile(filepath.Join(s.path, "cgroup.kill"), []byte("1"), 0o644) == nil
+}
+
+// cleanup removes the scope. The kernel refuses to rmdir a non-empty
+// cgroup: when called from finish the child has already exited and been
+// reaped, but if any process is still in the scope (e.g. when applyCgroup
+// fails after moving the child in) the removal fails and the scope is
+// left in place.
+func (s *cgroupState) cleanup() {
+	_ = os.Remove(s.path)
+}
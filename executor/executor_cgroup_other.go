@@ -0,0 +1,20 @@
+//go:build !windows && !linux
+// +build !windows,!linux
+
+package executor
+
+// cgroupState is unsupported outside Linux (placeholder for API
+// consistency with executor_cgroup_linux.go).
+type cgroupState struct{}
+
+func (s *cgroupState) peakMemBytes() int64 { return 0 }
+
+func (s *cgroupState) kill() bool { return false }
+
+func (s *cgroupState) cleanup() {}
+
+// applyCgroup is unsupported outside Linux; WithCgroup is a no-op here,
+// matching executor_windows.go rather than failing Start.
+func applyCgroup(c *Cmd) (*cgroupState, error) {
+	return nil, nil
+}
@@ -0,0 +1,24 @@
+//go:build linux
+// +build linux
+
+package executor
+
+import (
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// killPidfd signals pid via pidfd_open/pidfd_send_signal, which (unlike
+// kill(2)) targets a specific process instance rather than a PID number,
+// so it can't accidentally hit a reused PID if the original process has
+// already exited. It returns false if the pidfd APIs aren't available or
+// fail, so callers can fall back to a pgid kill.
+func killPidfd(pid int) bool {
+	fd, err := unix.PidfdOpen(pid, 0)
+	if err != nil {
+		return false
+	}
+	defer unix.Close(fd)
+	return unix.PidfdSendSignal(fd, syscall.SIGKILL, nil, 0) == nil
+}
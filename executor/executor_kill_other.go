@@ -0,0 +1,12 @@
+//go:build !windows && !linux
+// +build !windows,!linux
+
+package executor
+
+// killPidfd is unsupported outside Linux: pidfd_open/pidfd_send_signal
+// have no equivalent in golang.org/x/sys/unix for Darwin/BSD. It always
+// reports failure so callers fall back to a pgid kill, as killPlatform
+// already does.
+func killPidfd(pid int) bool {
+	return false
+}
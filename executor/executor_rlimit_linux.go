@@ -0,0 +1,22 @@
+//go:build linux
+// +build linux
+
+package executor
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/unix"
+)
+
+// applyRlimits applies any WithRLimits entries to the already-started
+// child via prlimit, before it does meaningful work.
+func applyRlimits(c *Cmd) error {
+	for resource, limit := range c.cfg.rlimits {
+		rl := unix.Rlimit{Cur: limit.Cur, Max: limit.Max}
+		if err := unix.Prlimit(c.execCmd.Process.Pid, resource, &rl, nil); err != nil {
+			return fmt.Errorf("%w: setrlimit(resource=%d): %v", ErrStart, resource, err)
+		}
+	}
+	return nil
+}
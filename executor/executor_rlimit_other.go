@@ -0,0 +1,12 @@
+//go:build !windows && !linux
+// +build !windows,!linux
+
+package executor
+
+// applyRlimits is unsupported outside Linux: prlimit(2) (needed to set
+// limits on the already-started child rather than the caller itself) has
+// no equivalent in golang.org/x/sys/unix for Darwin/BSD, so WithRLimits
+// is a no-op here rather than failing Start.
+func applyRlimits(c *Cmd) error {
+	return nil
+}
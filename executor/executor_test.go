@@ -12,12 +12,14 @@ import (
 	"os"
 	"os/exec"
 	"strings"
+	"sync"
 	"syscall"
 	"testing"
 	"time"
 
 	"github.com/itart-top/executor/executor"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // TestRun_Success tests successful command execution.
@@ -258,6 +260,197 @@ while true; do echo "parent $$ running"; sleep 1; done
 	}
 }
 
+// TestRun_KillProcessTree verifies that WithKillStrategy(KillProcessTree)
+// reaches grandchildren a plain pgid kill would miss, since the forked child
+// below calls setsid to leave the shell's process group.
+func TestRun_KillProcessTree(t *testing.T) {
+	script := `
+#!/bin/bash
+echo "Parent PID: $$"
+setsid bash -c 'while true; do echo "child $$ running"; sleep 1; done' &
+while true; do echo "parent $$ running"; sleep 1; done
+`
+	tmpFile := "test_fork_tree.sh"
+	if err := os.WriteFile(tmpFile, []byte(script), 0755); err != nil {
+		t.Fatalf("failed to create temp script: %v", err)
+	}
+	defer os.Remove(tmpFile)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var output strings.Builder
+	res := executor.Run(ctx, "bash",
+		executor.WithArgs(tmpFile),
+		executor.WithStdout(&output),
+		executor.WithStderr(&output),
+		executor.WithKillStrategy(executor.KillProcessTree),
+	)
+
+	if ctx.Err() == nil {
+		t.Errorf("expected context timeout, got nil")
+	}
+
+	var parentPID int
+	fmt.Sscanf(output.String(), "Parent PID: %d", &parentPID)
+	if parentPID > 0 && checkProcessExists(parentPID) {
+		t.Errorf("parent process %d still alive", parentPID)
+	}
+
+	if res.ExitCode == 0 {
+		t.Errorf("expected non-zero exit code on kill, got 0")
+	}
+}
+
+// TestRun_WithPTY tests that commands run under an allocated PTY, which
+// causes isatty checks to report a terminal.
+func TestRun_WithPTY(t *testing.T) {
+	result := executor.Run(
+		context.Background(),
+		"sh",
+		executor.WithArgs("-c", "test -t 1 && echo is-a-tty"),
+		executor.WithPTY(),
+	)
+
+	assert.NoError(t, result.Err)
+	assert.Equal(t, 0, result.ExitCode)
+	assert.Contains(t, result.Stdout, "is-a-tty")
+}
+
+// TestPrepare_StartWaitPid tests the persistent Cmd type: Start returns
+// once the child is running and Pid is available before Wait completes.
+func TestPrepare_StartWaitPid(t *testing.T) {
+	c := executor.Prepare(context.Background(), "sleep", executor.WithArgs("0.2"))
+
+	require.NoError(t, c.Start())
+	assert.Greater(t, c.Pid(), 0)
+
+	result := c.Wait()
+	assert.NoError(t, result.Err)
+	assert.Equal(t, 0, result.ExitCode)
+}
+
+// TestRun_WithGracefulShutdown tests that context cancellation sends the
+// graceful signal first and only escalates to kill after the grace period.
+func TestRun_WithGracefulShutdown(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	var output strings.Builder
+	result := executor.Run(ctx, "sh",
+		executor.WithArgs("-c", "trap 'echo got-term; exit 0' TERM; sleep 5"),
+		executor.WithStdout(&output),
+		executor.WithGracefulShutdown(syscall.SIGTERM, 2*time.Second),
+	)
+
+	assert.ErrorIs(t, result.Err, executor.ErrContextDone)
+	assert.Contains(t, output.String(), "got-term")
+}
+
+// TestResult_Assert tests the Expected/Assert assertion surface.
+func TestResult_Assert(t *testing.T) {
+	result := executor.Run(
+		context.Background(),
+		"sh",
+		executor.WithArgs("-c", "echo out; echo err >&2"),
+	)
+
+	result.Assert(t, executor.Expected{
+		ExitCode:    0,
+		OutContains: "out",
+		Stderr:      "err",
+	})
+	assert.True(t, result.Success())
+	assert.False(t, result.TimedOut())
+}
+
+// TestResult_Combined tests that stdout and stderr are both captured
+// together via Combined.
+func TestResult_Combined(t *testing.T) {
+	result := executor.Run(
+		context.Background(),
+		"sh",
+		executor.WithArgs("-c", "echo first; echo second >&2"),
+	)
+
+	assert.Contains(t, result.Combined(), "first")
+	assert.Contains(t, result.Combined(), "second")
+}
+
+// TestRun_WithStdoutLine tests that stdout lines are delivered to the
+// callback as they arrive, in order.
+func TestRun_WithStdoutLine(t *testing.T) {
+	var mu sync.Mutex
+	var lines []string
+
+	result := executor.Run(
+		context.Background(),
+		"sh",
+		executor.WithArgs("-c", "echo one; echo two; echo three"),
+		executor.WithStdoutLine(func(line string) {
+			mu.Lock()
+			lines = append(lines, line)
+			mu.Unlock()
+		}),
+	)
+
+	assert.NoError(t, result.Err)
+	assert.Equal(t, []string{"one", "two", "three"}, lines)
+}
+
+// TestRun_WithOutputChannel tests that WithOutputChannel delivers both
+// streams and that Wait drains the channel before returning.
+func TestRun_WithOutputChannel(t *testing.T) {
+	events := make(chan executor.OutputEvent, 16)
+
+	result := executor.Run(
+		context.Background(),
+		"sh",
+		executor.WithArgs("-c", "echo out; echo err >&2"),
+		executor.WithOutputChannel(events),
+	)
+	close(events)
+
+	assert.NoError(t, result.Err)
+
+	var gotOut, gotErr bool
+	for ev := range events {
+		switch ev.Stream {
+		case executor.Stdout:
+			gotOut = gotOut || ev.Line == "out"
+		case executor.Stderr:
+			gotErr = gotErr || ev.Line == "err"
+		}
+	}
+	assert.True(t, gotOut, "expected an stdout event")
+	assert.True(t, gotErr, "expected a stderr event")
+}
+
+// TestRun_WithRLimits tests that rlimits are applied to the child before
+// it does meaningful work.
+func TestRun_WithRLimits(t *testing.T) {
+	result := executor.Run(
+		context.Background(),
+		"sh",
+		executor.WithArgs("-c", "ulimit -n"),
+		executor.WithRLimits(map[int]executor.Rlimit{
+			syscall.RLIMIT_NOFILE: {Cur: 64, Max: 64},
+		}),
+	)
+
+	assert.NoError(t, result.Err)
+	assert.Equal(t, "64\n", result.Stdout)
+}
+
+// TestRun_ResourceUsage tests that rusage is populated from the child's
+// exit status.
+func TestRun_ResourceUsage(t *testing.T) {
+	result := executor.Run(context.Background(), "sh", executor.WithArgs("-c", "echo hi"))
+
+	assert.NoError(t, result.Err)
+	assert.Greater(t, result.ResourceUsage.MaxRSS, int64(0))
+}
+
 // TestRun_OutputTruncation tests that large output is truncated when maxOutput is set.
 func TestRun_OutputTruncation(t *testing.T) {
 	const maxBytes = 10 // 限制捕获最多 10 个字节
@@ -284,3 +477,75 @@ func TestRun_OutputTruncation(t *testing.T) {
 
 	// 外部 stdout/stderr 不受限制，可选择检查
 }
+
+// TestRunWithRetry_SucceedsEventually tests that a command which fails a
+// fixed number of times before succeeding is retried until it succeeds,
+// and that every attempt is returned.
+func TestRunWithRetry_SucceedsEventually(t *testing.T) {
+	counterFile := "test_retry_counter"
+	_ = os.Remove(counterFile)
+	defer os.Remove(counterFile)
+
+	script := fmt.Sprintf(`
+n=$(cat %[1]s 2>/dev/null || echo 0)
+n=$((n+1))
+echo "$n" > %[1]s
+if [ "$n" -lt 3 ]; then
+  exit 1
+fi
+exit 0
+`, counterFile)
+
+	results := executor.RunWithRetry(
+		context.Background(),
+		"sh",
+		executor.RetryPolicy{
+			MaxAttempts:    5,
+			InitialBackoff: 10 * time.Millisecond,
+			Multiplier:     1,
+		},
+		executor.WithArgs("-c", script),
+	)
+
+	require.Len(t, results, 3)
+	assert.Equal(t, 1, results[0].ExitCode)
+	assert.Equal(t, 1, results[1].ExitCode)
+	assert.Equal(t, 0, results[2].ExitCode)
+}
+
+// TestRunWithRetry_MaxAttempts tests that RunWithRetry stops after
+// MaxAttempts even if every attempt keeps failing.
+func TestRunWithRetry_MaxAttempts(t *testing.T) {
+	results := executor.RunWithRetry(
+		context.Background(),
+		"sh",
+		executor.RetryPolicy{
+			MaxAttempts:    3,
+			InitialBackoff: time.Millisecond,
+			Multiplier:     1,
+		},
+		executor.WithArgs("-c", "exit 1"),
+	)
+
+	assert.Len(t, results, 3)
+	for _, res := range results {
+		assert.Equal(t, 1, res.ExitCode)
+	}
+}
+
+// TestRunWithRetry_NoRetryOnContextDone tests that the default RetryOn
+// doesn't retry an attempt cut short by context cancellation.
+func TestRunWithRetry_NoRetryOnContextDone(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	results := executor.RunWithRetry(
+		ctx,
+		"sh",
+		executor.RetryPolicy{MaxAttempts: 5, InitialBackoff: time.Millisecond},
+		executor.WithArgs("-c", "sleep 5"),
+	)
+
+	require.Len(t, results, 1)
+	assert.True(t, errors.Is(results[0].Err, executor.ErrContextDone))
+}
@@ -0,0 +1,317 @@
+//go:build !windows
+// +build !windows
+
+package executor
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/creack/pty"
+)
+
+// Credential represents UID/GID to run the command with (Unix only).
+type Credential struct {
+	UID uint32
+	GID uint32
+}
+
+// startPlatform builds and launches the child process for c, wiring up
+// the PTY (if requested) or plain stdio pipes.
+func startPlatform(c *Cmd) error {
+	execCmd := exec.CommandContext(c.ctx, c.cfg.cmd, c.cfg.args...)
+	execCmd.Dir = c.cfg.dir
+	execCmd.Env = append(os.Environ(), c.cfg.env...)
+	// run (in executor.go) owns every context-cancellation
+	// escalation path (WithGracefulShutdown, then WithKillStrategy);
+	// disable CommandContext's own automatic Process.Kill() on ctx.Done
+	// so it can't race rootPid's death against ours and reap it before a
+	// tree-aware strategy like KillProcessTree gets a chance to scan for
+	// descendants.
+	execCmd.Cancel = func() error { return nil }
+
+	sysAttr := &syscall.SysProcAttr{}
+	if !c.cfg.pty {
+		// Setsid (used below for PTY allocation) already creates a new
+		// process group, so only set this explicitly in the non-PTY path.
+		sysAttr.Setpgid = true
+	}
+	if c.cfg.credential != nil {
+		sysAttr.Credential = &syscall.Credential{
+			Uid: c.cfg.credential.UID,
+			Gid: c.cfg.credential.GID,
+		}
+	}
+	execCmd.SysProcAttr = sysAttr
+
+	if c.cfg.pty {
+		return startPTY(c, execCmd)
+	}
+
+	execCmd.Stdin = c.cfg.stdin
+	execCmd.Stdout = c.stdoutWriter()
+	execCmd.Stderr = c.stderrWriter()
+
+	if err := execCmd.Start(); err != nil {
+		return fmt.Errorf("%w: %v", ErrStart, err)
+	}
+	c.execCmd = execCmd
+	return applyRlimits(c)
+}
+
+// startPTY allocates a pseudo-terminal and attaches execCmd's
+// stdin/stdout/stderr to it, so commands that branch on isatty
+// (interactive shells, sudo prompts, colorized output) behave as they
+// would in a real terminal. A PTY merges stdout and stderr onto a single
+// stream, so both are captured from the master side into c.stdoutBuf;
+// c.stderrBuf is left untouched in this mode. Start rejects this
+// combined with WithStderrLine/WithOutputChannel (ErrPTYStreamSplit)
+// since neither can be split back into per-stream callbacks.
+func startPTY(c *Cmd, execCmd *exec.Cmd) error {
+	var size *pty.Winsize
+	if c.cfg.ptyRows > 0 || c.cfg.ptyCols > 0 {
+		size = &pty.Winsize{Rows: c.cfg.ptyRows, Cols: c.cfg.ptyCols}
+	}
+
+	ptmx, err := pty.StartWithSize(execCmd, size)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrStart, err)
+	}
+	c.execCmd = execCmd
+	c.ptmx = ptmx
+	if err := applyRlimits(c); err != nil {
+		return err
+	}
+
+	winch := make(chan os.Signal, 1)
+	signal.Notify(winch, syscall.SIGWINCH)
+	c.winchStop = func() { signal.Stop(winch) }
+	go func() {
+		for range winch {
+			_ = pty.InheritSize(os.Stdin, ptmx)
+		}
+	}()
+
+	if c.cfg.stdin != nil {
+		go func() { _, _ = io.Copy(ptmx, c.cfg.stdin) }()
+	}
+
+	c.copyDone = make(chan struct{})
+	go func() {
+		_, _ = io.Copy(c.stdoutWriter(), ptmx)
+		close(c.copyDone)
+	}()
+
+	return nil
+}
+
+// killPlatform terminates the child according to c.cfg.killStrategy,
+// falling back to a pgid kill if the chosen strategy can't be applied.
+func killPlatform(c *Cmd) {
+	if c.execCmd.Process == nil {
+		return
+	}
+	pid := c.execCmd.Process.Pid
+
+	switch c.cfg.killStrategy {
+	case KillProcessTree:
+		killProcessTree(pid)
+	case KillCgroup:
+		if c.cgroupState == nil || !c.cgroupState.kill() {
+			_ = syscall.Kill(-pid, syscall.SIGKILL)
+		}
+	case KillPidfd:
+		if !killPidfd(pid) {
+			_ = syscall.Kill(-pid, syscall.SIGKILL)
+		}
+	default: // KillPGID
+		_ = syscall.Kill(-pid, syscall.SIGKILL)
+	}
+}
+
+// killProcessTree walks /proc to find every descendant of rootPid
+// (transitively, via each process's PPid) and SIGKILLs them individually.
+// It repeats a few rounds since a process being killed may itself spawn
+// children between scans; this covers trees that escaped the pgid via
+// setsid/setpgid and so wouldn't be reached by a plain pgid kill.
+//
+// The descendant set is snapshotted before rootPid is killed, and grown
+// (never recomputed from rootPid) on later rounds: once rootPid exits and
+// is reaped, its already-discovered children are reparented to init, so
+// re-deriving ancestry from rootPid on a later scan would lose them.
+func killProcessTree(rootPid int) {
+	tracked := map[int]bool{rootPid: true}
+	for round := 0; round < 5; round++ {
+		ppids, err := procParentMap()
+		if err != nil {
+			if round == 0 {
+				// No /proc to walk (non-Linux): fall back to a pgid kill.
+				_ = syscall.Kill(-rootPid, syscall.SIGKILL)
+			}
+			break
+		}
+		grew := false
+		for _, pid := range descendantsOf(tracked, ppids) {
+			tracked[pid] = true
+			grew = true
+		}
+		for pid := range tracked {
+			_ = syscall.Kill(pid, syscall.SIGKILL)
+		}
+		if round > 0 && !grew {
+			return
+		}
+	}
+}
+
+// procParentMap scans /proc/*/stat and returns a map of pid -> ppid for
+// every process currently visible to us.
+func procParentMap() (map[int]int, error) {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return nil, err
+	}
+
+	ppids := make(map[int]int, len(entries))
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join("/proc", entry.Name(), "stat"))
+		if err != nil {
+			continue
+		}
+		// Fields are "pid (comm) state ppid ...": comm may itself contain
+		// spaces or parens, so resume parsing after the last ')'.
+		parenEnd := strings.LastIndexByte(string(data), ')')
+		if parenEnd == -1 {
+			continue
+		}
+		fields := strings.Fields(string(data[parenEnd+1:]))
+		if len(fields) < 2 {
+			continue
+		}
+		ppid, err := strconv.Atoi(fields[1])
+		if err != nil {
+			continue
+		}
+		ppids[pid] = ppid
+	}
+	return ppids, nil
+}
+
+// descendantsOf returns every pid in ppids that is a transitive descendant
+// of any pid in roots (but is not itself in roots), in no particular
+// order.
+func descendantsOf(roots map[int]bool, ppids map[int]int) []int {
+	var descendants []int
+	for pid := range ppids {
+		if roots[pid] {
+			continue
+		}
+		ancestor := pid
+		for depth := 0; depth < len(ppids); depth++ {
+			parent, ok := ppids[ancestor]
+			if !ok {
+				break
+			}
+			if roots[parent] {
+				descendants = append(descendants, pid)
+				break
+			}
+			ancestor = parent
+		}
+	}
+	return descendants
+}
+
+// killLastResort unconditionally SIGKILLs the child's process group. It is
+// invoked if killPlatform's chosen strategy and the cgroup fallback both
+// fail to bring the process down within killEscalationTimeout, so a
+// misbehaving KillStrategy can't wedge Cmd.Wait forever.
+func killLastResort(c *Cmd) {
+	if c.execCmd.Process == nil {
+		return
+	}
+	_ = syscall.Kill(-c.execCmd.Process.Pid, syscall.SIGKILL)
+}
+
+// sendSignalPlatform sends sig to the child's whole process group, so
+// foreground children of a shell receive it too.
+func sendSignalPlatform(c *Cmd, sig os.Signal) error {
+	if c.execCmd.Process == nil {
+		return nil
+	}
+	if s, ok := sig.(syscall.Signal); ok {
+		return syscall.Kill(-c.execCmd.Process.Pid, s)
+	}
+	return c.execCmd.Process.Signal(sig)
+}
+
+// finishPlatform releases PTY resources once the child has exited.
+func finishPlatform(c *Cmd) {
+	if c.ptmx == nil {
+		return
+	}
+	if c.winchStop != nil {
+		c.winchStop()
+	}
+	_ = c.ptmx.Close()
+	<-c.copyDone
+}
+
+// resourceUsageFromProcessState reads rusage (getrusage/wait4) from the
+// child's exit status. MaxRSS is normalized to bytes; the kernel reports
+// it in KiB on Linux but bytes on Darwin/BSD.
+func resourceUsageFromProcessState(ps *os.ProcessState) ResourceUsage {
+	if ps == nil {
+		return ResourceUsage{}
+	}
+	ru, ok := ps.SysUsage().(*syscall.Rusage)
+	if !ok {
+		return ResourceUsage{}
+	}
+
+	maxRSS := int64(ru.Maxrss)
+	if runtime.GOOS == "linux" {
+		maxRSS *= 1024
+	}
+
+	return ResourceUsage{
+		MaxRSS:  maxRSS,
+		UserCPU: timevalToDuration(ru.Utime),
+		SysCPU:  timevalToDuration(ru.Stime),
+	}
+}
+
+func timevalToDuration(tv syscall.Timeval) time.Duration {
+	return time.Duration(tv.Sec)*time.Second + time.Duration(tv.Usec)*time.Microsecond
+}
+
+func exitCodeFromError(err error) int {
+	exitErr, ok := err.(*exec.ExitError)
+	if !ok {
+		return -1
+	}
+	ws, ok := exitErr.Sys().(syscall.WaitStatus)
+	if !ok {
+		return -1
+	}
+	if ws.Exited() {
+		return ws.ExitStatus()
+	}
+	if ws.Signaled() {
+		return 128 + int(ws.Signal())
+	}
+	return -1
+}
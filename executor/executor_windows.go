@@ -0,0 +1,87 @@
+//go:build windows
+// +build windows
+
+package executor
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// Credential is unsupported on Windows (placeholder for API consistency).
+type Credential struct {
+	UID uint32
+	GID uint32
+}
+
+func startPlatform(c *Cmd) error {
+	if c.cfg.pty {
+		return ErrPTYUnsupported
+	}
+
+	execCmd := exec.CommandContext(c.ctx, c.cfg.cmd, c.cfg.args...)
+	execCmd.Dir = c.cfg.dir
+	execCmd.Env = append(os.Environ(), c.cfg.env...)
+
+	// Windows doesn't support syscall.Credential or pgid.
+	execCmd.Stdin = c.cfg.stdin
+	execCmd.Stdout = c.stdoutWriter()
+	execCmd.Stderr = c.stderrWriter()
+
+	if err := execCmd.Start(); err != nil {
+		return fmt.Errorf("%w: %v", ErrStart, err)
+	}
+	c.execCmd = execCmd
+	return nil
+}
+
+// killPlatform kills the process directly; Windows has no process-group
+// signal equivalent to SIGKILL on a pgid, so c.cfg.killStrategy is
+// ignored here and Process.Kill() is always used.
+func killPlatform(c *Cmd) {
+	if c.execCmd.Process != nil {
+		_ = c.execCmd.Process.Kill()
+	}
+}
+
+// killLastResort is the same as killPlatform here: Windows has no pgid
+// concept to fall back to, so Process.Kill() is already unconditional.
+func killLastResort(c *Cmd) {
+	killPlatform(c)
+}
+
+// sendSignalPlatform ignores sig and kills the process: os.Process.Signal
+// only supports os.Kill on Windows.
+func sendSignalPlatform(c *Cmd, sig os.Signal) error {
+	if c.execCmd.Process == nil {
+		return nil
+	}
+	return c.execCmd.Process.Kill()
+}
+
+func finishPlatform(c *Cmd) {}
+
+func exitCodeFromError(err error) int {
+	return -1
+}
+
+// resourceUsageFromProcessState is unsupported on Windows; rusage fields
+// always read zero.
+func resourceUsageFromProcessState(ps *os.ProcessState) ResourceUsage {
+	return ResourceUsage{}
+}
+
+// cgroupState is unsupported on Windows (placeholder for API consistency).
+type cgroupState struct{}
+
+func (s *cgroupState) peakMemBytes() int64 { return 0 }
+
+func (s *cgroupState) kill() bool { return false }
+
+func (s *cgroupState) cleanup() {}
+
+// applyCgroup is unsupported on Windows; WithCgroup has no effect here.
+func applyCgroup(c *Cmd) (*cgroupState, error) {
+	return nil, nil
+}